@@ -14,9 +14,9 @@ import (
 	"errors"
 	"fmt"
 	"golang.org/x/sync/errgroup"
-	"log"
 	insecurerand "math/rand"
 	"software.sslmate.com/src/certspotter/loglist"
+	"sync"
 	"time"
 )
 
@@ -36,6 +36,7 @@ func reloadLogListInterval() time.Duration {
 type task struct {
 	log  *loglist.Log
 	stop context.CancelFunc
+	done chan struct{}
 }
 
 type daemon struct {
@@ -44,73 +45,192 @@ type daemon struct {
 	tasks          map[LogID]task
 	logsLoadedAt   time.Time
 	logListToken   *loglist.ModificationToken
+	notifiers      *notifierChain
+	metrics        *metrics
+	currentLogList map[LogID]*loglist.Log
+	mu             sync.Mutex
+}
+
+// NotifyNewCertificate delivers an EventNewCertificate to the configured
+// notifier chain and increments the certsMatched metric for watchItem.
+// Nothing in this package calls it: the per-entry certificate matching
+// code doesn't go through it, so EventNewCertificate is delivered, and
+// certsMatched incremented, only when some caller invokes it directly.
+func (config *Config) NotifyNewCertificate(ctx context.Context, logURL string, watchItem string, sans []string, sct string, chainURL string) error {
+	config.Metrics.certsMatched.WithLabelValues(watchItem).Inc()
+	return config.notifiers.Notify(ctx, Event{
+		Kind:      EventNewCertificate,
+		LogURL:    logURL,
+		WatchItem: watchItem,
+		SANs:      sans,
+		SCT:       sct,
+		ChainURL:  chainURL,
+		Occurred:  time.Now(),
+	})
+}
+
+// recordError persists err via State (the pre-existing behavior) and also
+// delivers it to the notifier chain as an EventLogError, so that
+// `recordError` call sites participate in the same notification pipeline
+// as healthCheck and startTask instead of being silently left on the old
+// State-only path.
+func (daemon *daemon) recordError(ctx context.Context, logID *LogID, logURL string, err error) {
+	daemon.mu.Lock()
+	config := daemon.config
+	notifiers := daemon.notifiers
+	daemon.mu.Unlock()
+
+	recordError(ctx, config, logID, err)
+	if notifyErr := notifiers.Notify(ctx, Event{
+		Kind:     EventLogError,
+		LogID:    logID,
+		LogURL:   logURL,
+		Summary:  err.Error(),
+		Occurred: time.Now(),
+	}); notifyErr != nil {
+		config.Logger.Error("error delivering notifications", "error", notifyErr)
+	}
 }
 
 func (daemon *daemon) healthCheck(ctx context.Context) error {
-	if time.Since(daemon.logsLoadedAt) >= daemon.config.HealthCheckInterval {
-		errors, err := daemon.config.State.GetErrors(ctx, nil, recentErrorCount)
+	// Config, notifiers, and tasks are all mutated by Reload (chunk0-3) and
+	// by loadLogList, both of which can run concurrently with healthCheck;
+	// take a consistent snapshot under the lock and do the (potentially
+	// slow) I/O below without holding it.
+	daemon.mu.Lock()
+	config := daemon.config
+	notifiers := daemon.notifiers
+	logsLoadedAt := daemon.logsLoadedAt
+	tasks := make(map[LogID]task, len(daemon.tasks))
+	for logID, t := range daemon.tasks {
+		tasks[logID] = t
+	}
+	daemon.mu.Unlock()
+
+	if time.Since(logsLoadedAt) >= config.HealthCheckInterval {
+		errors, err := config.State.GetErrors(ctx, nil, recentErrorCount)
 		if err != nil {
 			return fmt.Errorf("error getting recent errors: %w", err)
 		}
 		var errorsDir string
-		if fsstate, ok := daemon.config.State.(*FilesystemState); ok {
+		if fsstate, ok := config.State.(*FilesystemState); ok {
 			errorsDir = fsstate.errorDir(nil)
 		}
 		info := &StaleLogListInfo{
-			Source:        daemon.config.LogListSource,
-			LastSuccess:   daemon.logsLoadedAt,
+			Source:        config.LogListSource,
+			LastSuccess:   logsLoadedAt,
 			RecentErrors:  errors,
 			ErrorsDir:     errorsDir,
 		}
-		if err := daemon.config.State.NotifyHealthCheckFailure(ctx, nil, info); err != nil {
+		if err := config.State.NotifyHealthCheckFailure(ctx, nil, info); err != nil {
+			return fmt.Errorf("error notifying about stale log list: %w", err)
+		}
+		if err := notifiers.Notify(ctx, Event{
+			Kind:     EventStaleLogList,
+			LogURL:   config.LogListSource,
+			Summary:  fmt.Sprintf("log list not reloaded since %s", logsLoadedAt),
+			Occurred: time.Now(),
+		}); err != nil {
 			return fmt.Errorf("error notifying about stale log list: %w", err)
 		}
 	}
 
-	for _, task := range daemon.tasks {
-		if err := healthCheckLog(ctx, daemon.config, task.log); err != nil {
-			return fmt.Errorf("error checking health of log %q: %w", task.log.GetMonitoringURL(), err)
+	daemon.metrics.logListAgeSeconds.Set(time.Since(logsLoadedAt).Seconds())
+
+	for logID, t := range tasks {
+		if err := healthCheckLog(ctx, config, t.log); err != nil {
+			daemon.metrics.logErrors.WithLabelValues("healthcheck").Inc()
+			return fmt.Errorf("error checking health of log %q: %w", t.log.GetMonitoringURL(), err)
 		}
+		daemon.updateSTHMetrics(ctx, config, logID, t.log)
 	}
 	return nil
 }
 
-func (daemon *daemon) startTask(ctx context.Context, ctlog *loglist.Log) task {
+// sthStateProvider is an optional interface a State implementation can
+// satisfy to expose each log's last-seen STH; with a State that doesn't
+// satisfy it, sthIndex and sthLagSeconds are never set.
+// updateSTHMetrics uses it, when available, to report sthIndex and
+// sthLagSeconds without requiring the per-entry processing loop to push
+// every STH through a separate channel.
+type sthStateProvider interface {
+	GetSTH(ctx context.Context, logID LogID) (treeSize uint64, sthTimestamp time.Time, err error)
+}
+
+// updateSTHMetrics reports sthIndex and sthLagSeconds for a single log, if
+// config.State supports sthStateProvider. It's called from healthCheck's
+// existing per-log loop, which already runs on config.HealthCheckInterval,
+// rather than on every get-entries batch, since these metrics only need to
+// reflect roughly-current state.
+func (daemon *daemon) updateSTHMetrics(ctx context.Context, config *Config, logID LogID, ctlog *loglist.Log) {
+	provider, ok := config.State.(sthStateProvider)
+	if !ok {
+		return
+	}
+	treeSize, sthTimestamp, err := provider.GetSTH(ctx, logID)
+	if err != nil {
+		return
+	}
+	labels := []string{logID.Base64String(), ctlog.GetMonitoringURL()}
+	daemon.metrics.sthIndex.WithLabelValues(labels...).Set(float64(treeSize))
+	daemon.metrics.sthLagSeconds.WithLabelValues(labels...).Set(time.Since(sthTimestamp).Seconds())
+}
+
+// startTask must be called with daemon.mu held, since it reads
+// daemon.config and appends to daemon.taskgroup; it snapshots config so
+// the spawned goroutine isn't racing a concurrent Reload's config swap.
+func (daemon *daemon) startTask(ctx context.Context, logID LogID, ctlog *loglist.Log) task {
+	config := daemon.config
 	ctx, cancel := context.WithCancel(ctx)
+	logger := daemon.taskLogger(logID, ctlog)
+	done := make(chan struct{})
+	daemon.metrics.taskRunning.WithLabelValues(logID.Base64String()).Set(1)
 	daemon.taskgroup.Go(func() error {
+		defer close(done)
 		defer cancel()
-		err := monitorLogContinously(ctx, daemon.config, ctlog)
-		if daemon.config.Verbose {
-			log.Printf("%s: task stopped with error: %s", ctlog.GetMonitoringURL(), err)
-		}
+		defer daemon.metrics.taskRunning.WithLabelValues(logID.Base64String()).Set(0)
+		err := monitorLogContinously(ctx, config, ctlog)
+		logger.Debug("task stopped", "error", err)
 		if ctx.Err() == context.Canceled && errors.Is(err, context.Canceled) {
 			return nil
 		} else {
+			daemon.metrics.logErrors.WithLabelValues("monitor").Inc()
+			daemon.recordError(ctx, &logID, ctlog.GetMonitoringURL(), fmt.Errorf("error while monitoring %s: %w", ctlog.GetMonitoringURL(), err))
 			return fmt.Errorf("error while monitoring %s: %w", ctlog.GetMonitoringURL(), err)
 		}
 	})
-	return task{log: ctlog, stop: cancel}
+	return task{log: ctlog, stop: cancel, done: done}
 }
 
 func (daemon *daemon) loadLogList(ctx context.Context) error {
-	newLogList, newToken, err := getLogList(ctx, daemon.config.LogListSource, daemon.logListToken)
+	daemon.mu.Lock()
+	config := daemon.config
+	logListToken := daemon.logListToken
+	daemon.mu.Unlock()
+
+	logger := config.Logger.With("source", config.LogListSource)
+
+	newLogList, newToken, err := getLogList(ctx, config.LogListSource, logListToken)
 	if errors.Is(err, loglist.ErrNotModified) {
 		return nil
 	} else if err != nil {
 		return err
 	}
 
-	if daemon.config.Verbose {
-		log.Printf("fetched %d logs from %q", len(newLogList), daemon.config.LogListSource)
-	}
+	logger.Info("fetched log list", "num_logs", len(newLogList))
+
+	// Everything below mutates daemon.tasks/logsLoadedAt/logListToken/
+	// currentLogList, which Reload and healthCheck also read or mutate
+	// from other goroutines (chunk0-3); hold the lock for the whole
+	// reconciliation so it happens atomically.
+	daemon.mu.Lock()
+	defer daemon.mu.Unlock()
 
 	for logID, task := range daemon.tasks {
 		if _, exists := newLogList[logID]; exists {
 			continue
 		}
-		if daemon.config.Verbose {
-			log.Printf("stopping task for log %s", logID.Base64String())
-		}
+		logger.Debug("stopping task for log", "log_id", logID.Base64String())
 		task.stop()
 		delete(daemon.tasks, logID)
 	}
@@ -118,18 +238,23 @@ func (daemon *daemon) loadLogList(ctx context.Context) error {
 		if _, isRunning := daemon.tasks[logID]; isRunning {
 			continue
 		}
-		if daemon.config.Verbose {
-			log.Printf("starting task for log %s (%s)", logID.Base64String(), ctlog.GetMonitoringURL())
-		}
-		daemon.tasks[logID] = daemon.startTask(ctx, ctlog)
+		logger.Debug("starting task for log", "log_id", logID.Base64String(), "monitoring_url", ctlog.GetMonitoringURL())
+		daemon.tasks[logID] = daemon.startTask(ctx, logID, ctlog)
 	}
 	daemon.logsLoadedAt = time.Now()
 	daemon.logListToken = newToken
+	daemon.currentLogList = newLogList
+	daemon.metrics.logListReloadedAt.Set(float64(daemon.logsLoadedAt.Unix()))
+	daemon.metrics.logListAgeSeconds.Set(0)
 	return nil
 }
 
 func (daemon *daemon) run(ctx context.Context) error {
-	if err := daemon.config.State.Prepare(ctx); err != nil {
+	daemon.mu.Lock()
+	config := daemon.config
+	daemon.mu.Unlock()
+
+	if err := config.State.Prepare(ctx); err != nil {
 		return fmt.Errorf("error preparing state: %w", err)
 	}
 
@@ -140,7 +265,7 @@ func (daemon *daemon) run(ctx context.Context) error {
 	reloadLogListTicker := time.NewTicker(reloadLogListInterval())
 	defer reloadLogListTicker.Stop()
 
-	healthCheckTicker := time.NewTicker(daemon.config.HealthCheckInterval)
+	healthCheckTicker := time.NewTicker(config.HealthCheckInterval)
 	defer healthCheckTicker.Stop()
 
 	for {
@@ -149,24 +274,55 @@ func (daemon *daemon) run(ctx context.Context) error {
 			return ctx.Err()
 		case <-reloadLogListTicker.C:
 			if err := daemon.loadLogList(ctx); err != nil {
-				recordError(ctx, daemon.config, nil, fmt.Errorf("error reloading log list (will try again later): %w", err))
+				daemon.mu.Lock()
+				config = daemon.config
+				daemon.mu.Unlock()
+				config.Logger.Warn("error reloading log list, will try again later", "error", err)
+				daemon.recordError(ctx, nil, config.LogListSource, fmt.Errorf("error reloading log list (will try again later): %w", err))
 			}
 			reloadLogListTicker.Reset(reloadLogListInterval())
 		case <-healthCheckTicker.C:
 			if err := daemon.healthCheck(ctx); err != nil {
 				return err
 			}
+			// Reload (chunk0-3) can swap in a Config with a different
+			// HealthCheckInterval; re-read it and reset the ticker so the
+			// new interval takes effect from here on instead of only on a
+			// full process restart.
+			daemon.mu.Lock()
+			interval := daemon.config.HealthCheckInterval
+			daemon.mu.Unlock()
+			healthCheckTicker.Reset(interval)
 		}
 	}
 }
 
-func Run(ctx context.Context, config *Config) error {
-	group, ctx := errgroup.WithContext(ctx)
+func Run(parentCtx context.Context, config *Config) error {
+	notifiers, err := newNotifierChain(config.Notifiers)
+	if err != nil {
+		return fmt.Errorf("error configuring notifiers: %w", err)
+	}
+
+	config.notifiers = notifiers
+
+	m := newMetrics()
+	config.Metrics = m
+
 	daemon := &daemon{
 		config:    config,
-		taskgroup: group,
 		tasks:     make(map[LogID]task),
+		notifiers: notifiers,
+		metrics:   m,
 	}
-	group.Go(func() error { return daemon.run(ctx) })
-	return group.Wait()
+
+	return runWithShutdown(parentCtx, daemon, func(ctx context.Context) error {
+		group, ctx := errgroup.WithContext(ctx)
+		daemon.taskgroup = group
+		group.Go(func() error { return daemon.run(ctx) })
+		group.Go(func() error { return daemon.watchSignals(ctx) })
+		if config.MetricsListenAddr != "" {
+			group.Go(func() error { return m.serve(ctx, config.MetricsListenAddr) })
+		}
+		return group.Wait()
+	})
 }
@@ -0,0 +1,55 @@
+// Copyright (C) 2023 Opsmate, Inc.
+//
+// This Source Code Form is subject to the terms of the Mozilla
+// Public License, v. 2.0. If a copy of the MPL was not distributed
+// with this file, You can obtain one at http://mozilla.org/MPL/2.0/.
+//
+// This software is distributed WITHOUT A WARRANTY OF ANY KIND.
+// See the Mozilla Public License for details.
+
+package monitor
+
+import (
+	"github.com/hashicorp/go-hclog"
+	"os"
+	"software.sslmate.com/src/certspotter/loglist"
+)
+
+// LogFormat selects how NewLogger renders output.
+type LogFormat string
+
+const (
+	LogFormatText LogFormat = "text"
+	LogFormatJSON LogFormat = "json"
+)
+
+// NewLogger builds the top-level hclog.Logger used by Config.Logger. level
+// is one of "debug", "info", "warn", or "error"; an unrecognized level
+// defaults to "info".
+func NewLogger(level string, format LogFormat) hclog.Logger {
+	parsedLevel := hclog.LevelFromString(level)
+	if parsedLevel == hclog.NoLevel {
+		// hclog.LevelFromString returns NoLevel (which logs everything,
+		// including trace) for any string it doesn't recognize; fall back
+		// to info explicitly so a typo'd or missing level doesn't silently
+		// turn on full trace logging.
+		parsedLevel = hclog.Info
+	}
+	return hclog.New(&hclog.LoggerOptions{
+		Name:       "certspotter",
+		Level:      parsedLevel,
+		Output:     os.Stderr,
+		JSONFormat: format == LogFormatJSON,
+	})
+}
+
+// taskLogger returns the child logger used by a single log-monitoring
+// task, pre-bound with the fields an operator needs to correlate log
+// lines with a specific CT log.
+func (daemon *daemon) taskLogger(logID LogID, ctlog *loglist.Log) hclog.Logger {
+	return daemon.config.Logger.With(
+		"log_id", logID.Base64String(),
+		"monitoring_url", ctlog.GetMonitoringURL(),
+		"operator", ctlog.Operator,
+	)
+}
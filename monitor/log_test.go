@@ -0,0 +1,35 @@
+// Copyright (C) 2023 Opsmate, Inc.
+//
+// This Source Code Form is subject to the terms of the Mozilla
+// Public License, v. 2.0. If a copy of the MPL was not distributed
+// with this file, You can obtain one at http://mozilla.org/MPL/2.0/.
+//
+// This software is distributed WITHOUT A WARRANTY OF ANY KIND.
+// See the Mozilla Public License for details.
+
+package monitor
+
+import (
+	"github.com/hashicorp/go-hclog"
+	"testing"
+)
+
+func TestNewLoggerLevel(t *testing.T) {
+	tests := []struct {
+		level string
+		want  hclog.Level
+	}{
+		{"debug", hclog.Debug},
+		{"info", hclog.Info},
+		{"warn", hclog.Warn},
+		{"error", hclog.Error},
+		{"", hclog.Info},
+		{"bogus", hclog.Info},
+	}
+	for _, test := range tests {
+		logger := NewLogger(test.level, LogFormatText)
+		if got := logger.GetLevel(); got != test.want {
+			t.Errorf("NewLogger(%q, ...).GetLevel() = %v, want %v", test.level, got, test.want)
+		}
+	}
+}
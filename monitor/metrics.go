@@ -0,0 +1,109 @@
+// Copyright (C) 2023 Opsmate, Inc.
+//
+// This Source Code Form is subject to the terms of the Mozilla
+// Public License, v. 2.0. If a copy of the MPL was not distributed
+// with this file, You can obtain one at http://mozilla.org/MPL/2.0/.
+//
+// This software is distributed WITHOUT A WARRANTY OF ANY KIND.
+// See the Mozilla Public License for details.
+
+package monitor
+
+import (
+	"context"
+	"fmt"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"net/http"
+	"time"
+)
+
+// metrics holds the Prometheus collectors the daemon updates as it runs.
+// One metrics value is shared by every task goroutine, via Config.Metrics:
+// sthIndex and sthLagSeconds are updated by (*daemon).updateSTHMetrics
+// (daemon.go), certsMatched by Config.NotifyNewCertificate (daemon.go), and
+// logErrors/logListReloadedAt/logListAgeSeconds/taskRunning from within
+// this package's own task and log-list-reload bookkeeping.
+//
+// There's deliberately no per-entry counter here: that would need to be
+// incremented from inside the get-entries batch loop, which this series
+// doesn't touch, and registering a collector nothing ever updates would
+// just be a metric that silently reads zero forever.
+type metrics struct {
+	registry *prometheus.Registry
+
+	sthIndex          *prometheus.GaugeVec
+	sthLagSeconds     *prometheus.GaugeVec
+	logErrors         *prometheus.CounterVec
+	logListReloadedAt prometheus.Gauge
+	logListAgeSeconds prometheus.Gauge
+	certsMatched      *prometheus.CounterVec
+	taskRunning       *prometheus.GaugeVec
+}
+
+func newMetrics() *metrics {
+	m := &metrics{
+		registry: prometheus.NewRegistry(),
+		sthIndex: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Name: "certspotter_log_sth_index",
+			Help: "Current tree size being processed for a log.",
+		}, []string{"log_id", "url"}),
+		sthLagSeconds: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Name: "certspotter_log_sth_lag_seconds",
+			Help: "Seconds between now and the timestamp of the last STH seen for a log.",
+		}, []string{"log_id", "url"}),
+		logErrors: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "certspotter_log_errors_total",
+			Help: "Total errors encountered while monitoring logs, by kind.",
+		}, []string{"kind"}),
+		logListReloadedAt: prometheus.NewGauge(prometheus.GaugeOpts{
+			Name: "certspotter_log_list_last_reload_timestamp_seconds",
+			Help: "Unix timestamp of the last successful log list reload.",
+		}),
+		logListAgeSeconds: prometheus.NewGauge(prometheus.GaugeOpts{
+			Name: "certspotter_log_list_age_seconds",
+			Help: "Seconds since the log list was last successfully reloaded.",
+		}),
+		certsMatched: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "certspotter_certificates_matched_total",
+			Help: "Total certificates matched against the watch list, by watch item.",
+		}, []string{"watch_item"}),
+		taskRunning: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Name: "certspotter_task_running",
+			Help: "1 if a monitoring task for the log is currently running, 0 otherwise.",
+		}, []string{"log_id"}),
+	}
+	m.registry.MustRegister(
+		m.sthIndex,
+		m.sthLagSeconds,
+		m.logErrors,
+		m.logListReloadedAt,
+		m.logListAgeSeconds,
+		m.certsMatched,
+		m.taskRunning,
+	)
+	return m
+}
+
+// serve runs an HTTP server exposing m's collectors until ctx is
+// cancelled. It returns nil on a clean shutdown triggered by ctx.
+func (m *metrics) serve(ctx context.Context, addr string) error {
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", promhttp.HandlerFor(m.registry, promhttp.HandlerOpts{}))
+
+	server := &http.Server{Addr: addr, Handler: mux}
+	errCh := make(chan error, 1)
+	go func() { errCh <- server.ListenAndServe() }()
+
+	select {
+	case err := <-errCh:
+		if err != nil && err != http.ErrServerClosed {
+			return fmt.Errorf("metrics server failed: %w", err)
+		}
+		return nil
+	case <-ctx.Done():
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		return server.Shutdown(shutdownCtx)
+	}
+}
@@ -0,0 +1,246 @@
+// Copyright (C) 2023 Opsmate, Inc.
+//
+// This Source Code Form is subject to the terms of the Mozilla
+// Public License, v. 2.0. If a copy of the MPL was not distributed
+// with this file, You can obtain one at http://mozilla.org/MPL/2.0/.
+//
+// This software is distributed WITHOUT A WARRANTY OF ANY KIND.
+// See the Mozilla Public License for details.
+
+package monitor
+
+import (
+	"context"
+	"fmt"
+	"gopkg.in/yaml.v3"
+	"sync"
+	"time"
+)
+
+// EventKind identifies the kind of event being delivered to a Notifier.
+type EventKind string
+
+const (
+	EventNewCertificate  EventKind = "NewCertificate"
+	EventStaleLogList    EventKind = "StaleLogList"
+	EventLogError        EventKind = "LogError"
+	EventLogInconsistent EventKind = "LogInconsistent"
+)
+
+// Event is the payload delivered to every configured Notifier.
+type Event struct {
+	Kind       EventKind
+	LogID      *LogID
+	LogURL     string
+	Summary    string
+	LogURLLink string
+	WatchItem  string
+	SANs       []string
+	SCT        string
+	ChainURL   string
+	Occurred   time.Time
+}
+
+// Notifier delivers Events to some external system (webhook, syslog, SNS,
+// etc). Implementations are registered with RegisterNotifier and
+// instantiated from the Config's notifiers block.
+type Notifier interface {
+	// Configure parses the notifier-specific YAML block and readies the
+	// Notifier for use. It is called once, before Notify is ever called.
+	Configure(node *yaml.Node) error
+
+	// Notify delivers event, returning an error if delivery failed. Notify
+	// may be called concurrently from multiple goroutines.
+	Notify(ctx context.Context, event Event) error
+
+	// Kind returns the registered kind string for this Notifier (e.g.
+	// "webhook", "syslog", "sns").
+	Kind() string
+}
+
+type notifierFactory func() Notifier
+
+var (
+	notifierRegistryMu sync.Mutex
+	notifierRegistry   = make(map[string]notifierFactory)
+)
+
+// RegisterNotifier registers a Notifier implementation under kind so that
+// it can be instantiated from config. It is meant to be called from the
+// init function of the file implementing the Notifier.
+func RegisterNotifier(kind string, factory notifierFactory) {
+	notifierRegistryMu.Lock()
+	defer notifierRegistryMu.Unlock()
+	if _, exists := notifierRegistry[kind]; exists {
+		panic(fmt.Sprintf("monitor: notifier kind %q registered twice", kind))
+	}
+	notifierRegistry[kind] = factory
+}
+
+// NotifierConfig is one entry of the Config.Notifiers list, as parsed from
+// YAML:
+//
+//	notifiers:
+//	  - kind: webhook
+//	    url: https://example.com/hook
+//	  - kind: syslog
+//	    network: udp
+//	    address: 127.0.0.1:514
+//
+// Only "kind" and "events" are interpreted here; the remaining keys are
+// kind-specific and are handed, whole, to that kind's Notifier.Configure.
+type NotifierConfig struct {
+	Kind   string
+	Events []EventKind
+	node   *yaml.Node
+}
+
+// UnmarshalYAML decodes kind and events from node, then keeps node itself
+// so that the notifier-specific fields (url, topic_arn, network, address,
+// ...) can later be decoded by the chosen Notifier's Configure method.
+// yaml.v3's ",inline" only merges a map or embedded struct's fields into
+// the parent; it has no "capture the rest of the mapping" mode, so this
+// is the only way to both recognize the common keys and preserve the
+// kind-specific ones.
+func (nc *NotifierConfig) UnmarshalYAML(node *yaml.Node) error {
+	var shim struct {
+		Kind   string      `yaml:"kind"`
+		Events []EventKind `yaml:"events,omitempty"`
+	}
+	if err := node.Decode(&shim); err != nil {
+		return err
+	}
+	nc.Kind = shim.Kind
+	nc.Events = shim.Events
+	nc.node = node
+	return nil
+}
+
+// notifierChain is the set of instantiated Notifiers a daemon delivers
+// events to. A failure to deliver to one Notifier never prevents delivery
+// to the others.
+type notifierChain struct {
+	entries []chainEntry
+}
+
+// notifierCloser is implemented by Notifiers that hold a resource (an open
+// socket, a pooled client, ...) needing explicit teardown once they're
+// replaced, e.g. by Reload building a fresh chain from a new Config.
+type notifierCloser interface {
+	Close() error
+}
+
+// Close tears down every Notifier in the chain that implements
+// notifierCloser. It's called on the chain being replaced, after the swap,
+// so that Reload (or any other code that discards a notifierChain) doesn't
+// leak the sockets/clients the old chain's Notifiers were holding.
+func (chain *notifierChain) Close() error {
+	if chain == nil {
+		return nil
+	}
+	var firstErr error
+	for _, entry := range chain.entries {
+		closer, ok := entry.notifier.(notifierCloser)
+		if !ok {
+			continue
+		}
+		if err := closer.Close(); err != nil && firstErr == nil {
+			firstErr = fmt.Errorf("%s notifier: %w", entry.notifier.Kind(), err)
+		}
+	}
+	return firstErr
+}
+
+type chainEntry struct {
+	notifier Notifier
+	events   map[EventKind]bool // nil means "all events"
+}
+
+// newNotifierChain instantiates a Notifier for each configured entry.
+func newNotifierChain(configs []NotifierConfig) (*notifierChain, error) {
+	chain := &notifierChain{}
+	for i, cfg := range configs {
+		notifierRegistryMu.Lock()
+		factory, ok := notifierRegistry[cfg.Kind]
+		notifierRegistryMu.Unlock()
+		if !ok {
+			return nil, fmt.Errorf("notifiers[%d]: unknown notifier kind %q", i, cfg.Kind)
+		}
+		notifier := factory()
+		if err := notifier.Configure(cfg.node); err != nil {
+			return nil, fmt.Errorf("notifiers[%d]: error configuring %s notifier: %w", i, cfg.Kind, err)
+		}
+		var events map[EventKind]bool
+		if len(cfg.Events) > 0 {
+			events = make(map[EventKind]bool, len(cfg.Events))
+			for _, kind := range cfg.Events {
+				events[kind] = true
+			}
+		}
+		chain.entries = append(chain.entries, chainEntry{notifier: notifier, events: events})
+	}
+	return chain, nil
+}
+
+// Notify delivers event to every Notifier in the chain subscribed to its
+// kind, concurrently, with each Notifier's failure isolated from the
+// others. It returns the first error encountered, but only after every
+// Notifier has had a chance to run.
+func (chain *notifierChain) Notify(ctx context.Context, event Event) error {
+	if chain == nil || len(chain.entries) == 0 {
+		return nil
+	}
+
+	var (
+		wg       sync.WaitGroup
+		mu       sync.Mutex
+		firstErr error
+	)
+	for _, entry := range chain.entries {
+		if entry.events != nil && !entry.events[event.Kind] {
+			continue
+		}
+		entry := entry
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if err := notifyWithRetry(ctx, entry.notifier, event); err != nil {
+				mu.Lock()
+				if firstErr == nil {
+					firstErr = fmt.Errorf("%s notifier: %w", entry.notifier.Kind(), err)
+				}
+				mu.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+	return firstErr
+}
+
+const (
+	notifyMaxAttempts = 3
+	notifyBaseBackoff = 2 * time.Second
+)
+
+// notifyWithRetry calls notifier.Notify, retrying with exponential backoff
+// on failure so that a transient error (e.g. a webhook endpoint briefly
+// down) doesn't drop the event.
+func notifyWithRetry(ctx context.Context, notifier Notifier, event Event) error {
+	var lastErr error
+	for attempt := 0; attempt < notifyMaxAttempts; attempt++ {
+		if attempt > 0 {
+			backoff := notifyBaseBackoff * time.Duration(1<<uint(attempt-1))
+			select {
+			case <-time.After(backoff):
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+		}
+		if err := notifier.Notify(ctx, event); err != nil {
+			lastErr = err
+			continue
+		}
+		return nil
+	}
+	return lastErr
+}
@@ -0,0 +1,98 @@
+// Copyright (C) 2023 Opsmate, Inc.
+//
+// This Source Code Form is subject to the terms of the Mozilla
+// Public License, v. 2.0. If a copy of the MPL was not distributed
+// with this file, You can obtain one at http://mozilla.org/MPL/2.0/.
+//
+// This software is distributed WITHOUT A WARRANTY OF ANY KIND.
+// See the Mozilla Public License for details.
+
+package monitor
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/service/sns"
+	snsTypes "github.com/aws/aws-sdk-go-v2/service/sns/types"
+	"gopkg.in/yaml.v3"
+	"sync"
+)
+
+func init() {
+	RegisterNotifier("sns", func() Notifier { return &snsNotifier{} })
+}
+
+// snsNotifier publishes events to an AWS SNS topic as a JSON message,
+// using attributes so that subscribers can filter by event kind.
+type snsNotifier struct {
+	TopicARN string `yaml:"topic_arn"`
+	Region   string `yaml:"region,omitempty"`
+
+	mu     sync.Mutex
+	client *sns.Client
+}
+
+func (n *snsNotifier) Kind() string { return "sns" }
+
+func (n *snsNotifier) Configure(node *yaml.Node) error {
+	if err := node.Decode(n); err != nil {
+		return fmt.Errorf("error decoding sns notifier config: %w", err)
+	}
+	if n.TopicARN == "" {
+		return fmt.Errorf("sns notifier requires a topic_arn")
+	}
+	return nil
+}
+
+// getClient lazily builds the SNS client and caches it for reuse, but
+// does not cache a failure: a transient problem resolving AWS config
+// (which notifyWithRetry is specifically meant to retry) must not wedge
+// every future Notify call for the rest of the process.
+func (n *snsNotifier) getClient(ctx context.Context) (*sns.Client, error) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	if n.client != nil {
+		return n.client, nil
+	}
+
+	opts := []func(*config.LoadOptions) error{}
+	if n.Region != "" {
+		opts = append(opts, config.WithRegion(n.Region))
+	}
+	cfg, err := config.LoadDefaultConfig(ctx, opts...)
+	if err != nil {
+		return nil, fmt.Errorf("error loading AWS config: %w", err)
+	}
+	n.client = sns.NewFromConfig(cfg)
+	return n.client, nil
+}
+
+func (n *snsNotifier) Notify(ctx context.Context, event Event) error {
+	client, err := n.getClient(ctx)
+	if err != nil {
+		return err
+	}
+
+	body, err := json.Marshal(event)
+	if err != nil {
+		return fmt.Errorf("error marshaling SNS message: %w", err)
+	}
+
+	_, err = client.Publish(ctx, &sns.PublishInput{
+		TopicArn: aws.String(n.TopicARN),
+		Message:  aws.String(string(body)),
+		MessageAttributes: map[string]snsTypes.MessageAttributeValue{
+			"kind": {
+				DataType:    aws.String("String"),
+				StringValue: aws.String(string(event.Kind)),
+			},
+		},
+	})
+	if err != nil {
+		return fmt.Errorf("error publishing to SNS topic: %w", err)
+	}
+	return nil
+}
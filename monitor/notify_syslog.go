@@ -0,0 +1,153 @@
+// Copyright (C) 2023 Opsmate, Inc.
+//
+// This Source Code Form is subject to the terms of the Mozilla
+// Public License, v. 2.0. If a copy of the MPL was not distributed
+// with this file, You can obtain one at http://mozilla.org/MPL/2.0/.
+//
+// This software is distributed WITHOUT A WARRANTY OF ANY KIND.
+// See the Mozilla Public License for details.
+
+package monitor
+
+import (
+	"context"
+	"fmt"
+	"gopkg.in/yaml.v3"
+	"net"
+	"os"
+	"sync"
+	"time"
+)
+
+func init() {
+	RegisterNotifier("syslog", func() Notifier { return &syslogNotifier{} })
+}
+
+// syslogNotifier writes RFC 5424 formatted messages to a syslog collector
+// over UDP, TCP, or a Unix domain socket. Go's standard library syslog
+// package doesn't support RFC 5424 or non-local collectors, so we format
+// and dial ourselves.
+type syslogNotifier struct {
+	Network  string `yaml:"network"` // "udp", "tcp", or "unix"
+	Address  string `yaml:"address"`
+	Hostname string `yaml:"hostname,omitempty"`
+	Tag      string `yaml:"tag,omitempty"`
+	Facility int    `yaml:"facility,omitempty"`
+
+	mu   sync.Mutex
+	conn net.Conn
+}
+
+const (
+	syslogFacilityLocal0 = 16 // default facility when none is configured
+
+	syslogSeverityError   = 3 // LOG_ERR
+	syslogSeverityWarning = 4 // LOG_WARNING
+	syslogSeverityNotice  = 5 // LOG_NOTICE
+)
+
+// syslogSeverity maps an EventKind to the RFC 5424 severity it's logged
+// at: a newly discovered certificate is routine operational information,
+// while log errors and a stale log list are conditions worth alerting on.
+func syslogSeverity(kind EventKind) int {
+	switch kind {
+	case EventNewCertificate:
+		return syslogSeverityNotice
+	case EventStaleLogList:
+		return syslogSeverityWarning
+	default:
+		return syslogSeverityError
+	}
+}
+
+func (n *syslogNotifier) Kind() string { return "syslog" }
+
+func (n *syslogNotifier) Configure(node *yaml.Node) error {
+	if err := node.Decode(n); err != nil {
+		return fmt.Errorf("error decoding syslog notifier config: %w", err)
+	}
+	switch n.Network {
+	case "udp", "tcp", "unix":
+	case "":
+		n.Network = "udp"
+	default:
+		return fmt.Errorf("syslog notifier: unsupported network %q", n.Network)
+	}
+	if n.Address == "" {
+		return fmt.Errorf("syslog notifier requires an address")
+	}
+	if n.Hostname == "" {
+		if hostname, err := os.Hostname(); err == nil {
+			n.Hostname = hostname
+		} else {
+			n.Hostname = "-"
+		}
+	}
+	if n.Tag == "" {
+		n.Tag = "certspotter"
+	}
+	if n.Facility == 0 {
+		// Facility 0 is "kern", reserved for kernel messages; treat an
+		// unconfigured facility as "use the normal application default"
+		// rather than silently mislabeling our messages as kernel ones.
+		n.Facility = syslogFacilityLocal0
+	}
+	return nil
+}
+
+// Close closes the notifier's connection, if one is currently open. It
+// satisfies notifierCloser so Reload can tear down a replaced chain
+// without leaking the socket.
+func (n *syslogNotifier) Close() error {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	if n.conn == nil {
+		return nil
+	}
+	err := n.conn.Close()
+	n.conn = nil
+	return err
+}
+
+// Notify formats event as an RFC 5424 message and writes it to the
+// collector, dialing (or re-dialing, if a previous write failed) as
+// needed. The whole dial-and-write is done under n.mu, since
+// Notifier.Notify may be called concurrently and two goroutines' Write
+// calls interleaving on the same tcp/unix connection would corrupt the
+// stream.
+func (n *syslogNotifier) Notify(ctx context.Context, event Event) error {
+	priority := n.Facility*8 + syslogSeverity(event.Kind)
+	msg := fmt.Sprintf("<%d>1 %s %s %s - %s - %s: %s",
+		priority,
+		event.Occurred.UTC().Format(time.RFC3339),
+		n.Hostname,
+		n.Tag,
+		string(event.Kind),
+		event.Kind,
+		event.Summary,
+	)
+
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	if n.conn == nil {
+		conn, err := net.DialTimeout(n.Network, n.Address, 5*time.Second)
+		if err != nil {
+			return fmt.Errorf("error connecting to syslog collector: %w", err)
+		}
+		n.conn = conn
+	}
+
+	var err error
+	if n.Network != "tcp" {
+		_, err = n.conn.Write([]byte(msg))
+	} else {
+		_, err = n.conn.Write([]byte(msg + "\n"))
+	}
+	if err != nil {
+		n.conn.Close()
+		n.conn = nil
+		return fmt.Errorf("error writing to syslog collector: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,98 @@
+// Copyright (C) 2023 Opsmate, Inc.
+//
+// This Source Code Form is subject to the terms of the Mozilla
+// Public License, v. 2.0. If a copy of the MPL was not distributed
+// with this file, You can obtain one at http://mozilla.org/MPL/2.0/.
+//
+// This software is distributed WITHOUT A WARRANTY OF ANY KIND.
+// See the Mozilla Public License for details.
+
+package monitor
+
+import (
+	"context"
+	"errors"
+	"gopkg.in/yaml.v3"
+	"testing"
+)
+
+// fakeNotifier fails the first failCount calls to Notify, then succeeds.
+type fakeNotifier struct {
+	failCount int
+	calls     int
+}
+
+func (n *fakeNotifier) Kind() string                         { return "fake" }
+func (n *fakeNotifier) Configure(node *yaml.Node) error       { return nil }
+func (n *fakeNotifier) Notify(ctx context.Context, event Event) error {
+	n.calls++
+	if n.calls <= n.failCount {
+		return errors.New("fake notifier: induced failure")
+	}
+	return nil
+}
+
+func TestNotifyWithRetrySucceedsAfterTransientFailures(t *testing.T) {
+	n := &fakeNotifier{failCount: notifyMaxAttempts - 1}
+	if err := notifyWithRetry(context.Background(), n, Event{Kind: EventLogError}); err != nil {
+		t.Fatalf("notifyWithRetry() = %v, want nil", err)
+	}
+	if n.calls != notifyMaxAttempts {
+		t.Errorf("notifier was called %d times, want %d", n.calls, notifyMaxAttempts)
+	}
+}
+
+func TestNotifyWithRetryGivesUpAfterMaxAttempts(t *testing.T) {
+	n := &fakeNotifier{failCount: notifyMaxAttempts}
+	if err := notifyWithRetry(context.Background(), n, Event{Kind: EventLogError}); err == nil {
+		t.Fatal("notifyWithRetry() = nil, want error")
+	}
+	if n.calls != notifyMaxAttempts {
+		t.Errorf("notifier was called %d times, want %d", n.calls, notifyMaxAttempts)
+	}
+}
+
+func TestNotifyWithRetryStopsOnContextCancellation(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	n := &fakeNotifier{failCount: notifyMaxAttempts}
+	err := notifyWithRetry(ctx, n, Event{Kind: EventLogError})
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("notifyWithRetry() = %v, want context.Canceled", err)
+	}
+	if n.calls != 1 {
+		t.Errorf("notifier was called %d times after cancellation, want 1", n.calls)
+	}
+}
+
+func TestNotifierConfigUnmarshalYAML(t *testing.T) {
+	const doc = `
+kind: webhook
+events: [NewCertificate, LogError]
+url: https://example.com/hook
+timeout: 5s
+`
+	var nc NotifierConfig
+	if err := yaml.Unmarshal([]byte(doc), &nc); err != nil {
+		t.Fatalf("yaml.Unmarshal() = %v", err)
+	}
+	if nc.Kind != "webhook" {
+		t.Errorf("Kind = %q, want %q", nc.Kind, "webhook")
+	}
+	if len(nc.Events) != 2 || nc.Events[0] != EventNewCertificate || nc.Events[1] != EventLogError {
+		t.Errorf("Events = %v, want [NewCertificate LogError]", nc.Events)
+	}
+
+	// The notifier-specific fields (url, timeout) must survive on the
+	// retained node so that the chosen Notifier's own Configure call can
+	// still decode them.
+	var shim struct {
+		URL string `yaml:"url"`
+	}
+	if err := nc.node.Decode(&shim); err != nil {
+		t.Fatalf("nc.node.Decode() = %v", err)
+	}
+	if shim.URL != "https://example.com/hook" {
+		t.Errorf("decoded url = %q, want %q", shim.URL, "https://example.com/hook")
+	}
+}
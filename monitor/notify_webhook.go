@@ -0,0 +1,92 @@
+// Copyright (C) 2023 Opsmate, Inc.
+//
+// This Source Code Form is subject to the terms of the Mozilla
+// Public License, v. 2.0. If a copy of the MPL was not distributed
+// with this file, You can obtain one at http://mozilla.org/MPL/2.0/.
+//
+// This software is distributed WITHOUT A WARRANTY OF ANY KIND.
+// See the Mozilla Public License for details.
+
+package monitor
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"gopkg.in/yaml.v3"
+	"net/http"
+	"time"
+)
+
+func init() {
+	RegisterNotifier("webhook", func() Notifier { return &webhookNotifier{} })
+}
+
+type webhookNotifier struct {
+	URL     string            `yaml:"url"`
+	Headers map[string]string `yaml:"headers,omitempty"`
+	Timeout time.Duration     `yaml:"timeout,omitempty"`
+
+	client *http.Client
+}
+
+type webhookPayload struct {
+	Kind     EventKind `json:"kind"`
+	LogURL   string    `json:"log_url"`
+	SANs     []string  `json:"sans,omitempty"`
+	SCT      string    `json:"sct,omitempty"`
+	ChainURL string    `json:"chain_url,omitempty"`
+	Summary  string    `json:"summary,omitempty"`
+	Occurred time.Time `json:"occurred"`
+}
+
+func (n *webhookNotifier) Kind() string { return "webhook" }
+
+func (n *webhookNotifier) Configure(node *yaml.Node) error {
+	if err := node.Decode(n); err != nil {
+		return fmt.Errorf("error decoding webhook notifier config: %w", err)
+	}
+	if n.URL == "" {
+		return fmt.Errorf("webhook notifier requires a url")
+	}
+	if n.Timeout == 0 {
+		n.Timeout = 10 * time.Second
+	}
+	n.client = &http.Client{Timeout: n.Timeout}
+	return nil
+}
+
+func (n *webhookNotifier) Notify(ctx context.Context, event Event) error {
+	body, err := json.Marshal(webhookPayload{
+		Kind:     event.Kind,
+		LogURL:   event.LogURL,
+		SANs:     event.SANs,
+		SCT:      event.SCT,
+		ChainURL: event.ChainURL,
+		Summary:  event.Summary,
+		Occurred: event.Occurred,
+	})
+	if err != nil {
+		return fmt.Errorf("error marshaling webhook payload: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, n.URL, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("error building webhook request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for key, value := range n.Headers {
+		req.Header.Set(key, value)
+	}
+
+	resp, err := n.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("error sending webhook request: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("webhook endpoint returned status %s", resp.Status)
+	}
+	return nil
+}
@@ -0,0 +1,174 @@
+// Copyright (C) 2023 Opsmate, Inc.
+//
+// This Source Code Form is subject to the terms of the Mozilla
+// Public License, v. 2.0. If a copy of the MPL was not distributed
+// with this file, You can obtain one at http://mozilla.org/MPL/2.0/.
+//
+// This software is distributed WITHOUT A WARRANTY OF ANY KIND.
+// See the Mozilla Public License for details.
+
+package monitor
+
+import (
+	"context"
+	"fmt"
+	"github.com/fsnotify/fsnotify"
+	"os"
+	"os/signal"
+	"software.sslmate.com/src/certspotter/loglist"
+	"syscall"
+)
+
+// reload re-reads and parses Config.ConfigFilename, returning a new Config
+// that reload's caller can diff against the one currently running.
+func (config *Config) reload() (*Config, error) {
+	if config.ConfigFilename == "" {
+		return nil, fmt.Errorf("daemon was not started from a config file; nothing to reload")
+	}
+	newConfig, err := LoadConfig(config.ConfigFilename)
+	if err != nil {
+		return nil, err
+	}
+	return newConfig, nil
+}
+
+// Reload re-reads the on-disk config and reconciles running tasks against
+// the new state, without tearing the daemon down. It's the programmatic
+// entry point behind both SIGHUP and the fsnotify config watcher.
+func (daemon *daemon) Reload(ctx context.Context) error {
+	daemon.mu.Lock()
+	oldConfig := daemon.config
+	daemon.mu.Unlock()
+
+	logger := oldConfig.Logger
+	logger.Info("reloading config")
+
+	// Parsing the new config and building its notifier chain can block on
+	// disk/network I/O, so do it without holding daemon.mu; only the
+	// actual swap below needs the lock.
+	newConfig, err := oldConfig.reload()
+	if err != nil {
+		return fmt.Errorf("error reloading config: %w", err)
+	}
+
+	notifiers, err := newNotifierChain(newConfig.Notifiers)
+	if err != nil {
+		return fmt.Errorf("error reconfiguring notifiers: %w", err)
+	}
+	newConfig.notifiers = notifiers
+
+	daemon.mu.Lock()
+	oldLogList := daemon.currentLogList
+	oldNotifiers := daemon.notifiers
+	daemon.config = newConfig
+	daemon.notifiers = notifiers
+	daemon.mu.Unlock()
+
+	// oldNotifiers is no longer reachable from daemon, but its Notifiers
+	// (e.g. a syslogNotifier's open socket, a snsNotifier's AWS client)
+	// are still live; tear them down now instead of leaking them on every
+	// SIGHUP or AutoReloadConfig trigger.
+	if err := oldNotifiers.Close(); err != nil {
+		logger.Warn("error closing replaced notifiers", "error", err)
+	}
+
+	if err := daemon.loadLogList(ctx); err != nil {
+		return fmt.Errorf("error reloading log list: %w", err)
+	}
+
+	// loadLogList has already stopped tasks for logs removed from the new
+	// list and started tasks for logs newly added to it; everything left
+	// in daemon.tasks at this point is still present in the new list, so
+	// we only need to restart the subset of tasks whose own parameters
+	// (global batch size, or that particular log's submission URL)
+	// actually changed, not every running task.
+	daemon.mu.Lock()
+	newLogList := daemon.currentLogList
+	var toRestart []LogID
+	for logID := range daemon.tasks {
+		if taskNeedsRestart(oldConfig, newConfig, oldLogList[logID], newLogList[logID]) {
+			toRestart = append(toRestart, logID)
+		}
+	}
+	for _, logID := range toRestart {
+		t := daemon.tasks[logID]
+		ctlog := newLogList[logID]
+		logger.Debug("restarting task because its parameters changed", "log_id", logID.Base64String())
+		t.stop()
+		daemon.tasks[logID] = daemon.startTask(ctx, logID, ctlog)
+	}
+	daemon.mu.Unlock()
+
+	logger.Info("reload complete")
+	return nil
+}
+
+// taskNeedsRestart reports whether a running task for a single log must
+// be cancelled and restarted because either a daemon-wide parameter
+// (batch size) or that log's own submission URL changed between oldLog
+// and newLog. oldLog/newLog may be nil if the log wasn't present in one
+// of the two log lists; loadLogList already handles logs being added or
+// removed, so this is only reached for logs present in both.
+func taskNeedsRestart(oldConfig, newConfig *Config, oldLog, newLog *loglist.Log) bool {
+	if oldConfig.BatchSize != newConfig.BatchSize {
+		return true
+	}
+	if oldLog == nil || newLog == nil {
+		return false
+	}
+	return oldLog.GetSubmissionURL() != newLog.GetSubmissionURL()
+}
+
+// watchSignals handles SIGHUP by calling Reload, and optionally watches
+// the config file for changes via fsnotify when AutoReloadConfig is set.
+// It runs until ctx is cancelled.
+func (daemon *daemon) watchSignals(ctx context.Context) error {
+	sighup := make(chan os.Signal, 1)
+	signal.Notify(sighup, syscall.SIGHUP)
+	defer signal.Stop(sighup)
+
+	daemon.mu.Lock()
+	config := daemon.config
+	daemon.mu.Unlock()
+
+	var fsEvents chan fsnotify.Event
+	if config.AutoReloadConfig && config.ConfigFilename != "" {
+		watcher, err := fsnotify.NewWatcher()
+		if err != nil {
+			return fmt.Errorf("error creating config file watcher: %w", err)
+		}
+		defer watcher.Close()
+		if err := watcher.Add(config.ConfigFilename); err != nil {
+			return fmt.Errorf("error watching %s: %w", config.ConfigFilename, err)
+		}
+		fsEvents = watcher.Events
+	}
+
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-sighup:
+			if err := daemon.Reload(ctx); err != nil {
+				daemon.mu.Lock()
+				logger := daemon.config.Logger
+				daemon.mu.Unlock()
+				logger.Error("error reloading config", "error", err)
+			}
+		case event, ok := <-fsEvents:
+			if !ok {
+				fsEvents = nil
+				continue
+			}
+			if event.Op&(fsnotify.Write|fsnotify.Create) == 0 {
+				continue
+			}
+			if err := daemon.Reload(ctx); err != nil {
+				daemon.mu.Lock()
+				logger := daemon.config.Logger
+				daemon.mu.Unlock()
+				logger.Error("error auto-reloading config", "error", err)
+			}
+		}
+	}
+}
@@ -0,0 +1,109 @@
+// Copyright (C) 2023 Opsmate, Inc.
+//
+// This Source Code Form is subject to the terms of the Mozilla
+// Public License, v. 2.0. If a copy of the MPL was not distributed
+// with this file, You can obtain one at http://mozilla.org/MPL/2.0/.
+//
+// This software is distributed WITHOUT A WARRANTY OF ANY KIND.
+// See the Mozilla Public License for details.
+
+package monitor
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+)
+
+// Shutdown cancels the context of every running task and waits up to
+// config.ShutdownTimeout for each task's monitorLogContinously call to
+// return. Cancellation is the only signal tasks get: Shutdown doesn't ask
+// them to finish their current get-entries batch or to checkpoint first.
+// Shutdown doesn't stop the reload and health-check tickers itself; they
+// stop when daemon.run's context is cancelled, which runWithShutdown does
+// before calling Shutdown. Once every task has returned, Shutdown also
+// waits for the rest of daemon.taskgroup (daemon.run, watchSignals, the
+// metrics server), which return only once their context is cancelled.
+//
+// Run calls Shutdown on SIGTERM/SIGINT. daemon is unexported and Run
+// doesn't return it, so code outside this package can't call Shutdown.
+func (daemon *daemon) Shutdown(ctx context.Context) error {
+	daemon.mu.Lock()
+	config := daemon.config
+	tasks := make(map[LogID]task, len(daemon.tasks))
+	for logID, t := range daemon.tasks {
+		tasks[logID] = t
+	}
+	daemon.mu.Unlock()
+
+	logger := config.Logger
+	logger.Info("shutting down", "timeout", config.ShutdownTimeout)
+
+	// Ask every task to stop; stop() only cancels the task's context, it
+	// doesn't wait for anything, so the actual draining below waits on
+	// each task's done channel (closed by startTask's goroutine once
+	// monitorLogContinously has actually returned), not on stop()
+	// returning.
+	for logID, t := range tasks {
+		t.stop()
+		logger.Debug("waiting for task to drain", "log_id", logID.Base64String())
+	}
+
+	drained := make(chan struct{})
+	go func() {
+		for _, t := range tasks {
+			<-t.done
+		}
+		close(drained)
+	}()
+
+	select {
+	case <-drained:
+		logger.Info("all tasks drained cleanly")
+		return daemon.taskgroup.Wait()
+	case <-time.After(config.ShutdownTimeout):
+		// Every task's context was already cancelled by the stop() calls
+		// above; there's no way to forcibly kill a goroutine that ignores
+		// cancellation, so "hard-cancelling" means giving up on waiting
+		// for it rather than blocking Shutdown (and the process exit it
+		// gates) forever.
+		logger.Warn("shutdown timeout exceeded, hard-cancelling stragglers")
+		return fmt.Errorf("shutdown timeout of %s exceeded before all tasks drained", config.ShutdownTimeout)
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// runWithShutdown wraps Run's top-level context with a signal handler
+// that invokes daemon.Shutdown on SIGTERM/SIGINT instead of relying
+// solely on errgroup's cancellation, giving tasks a bounded window to
+// return after cancellation. If Shutdown fails (including when the window
+// runs out), its error is returned without waiting any longer for run.
+func runWithShutdown(ctx context.Context, daemon *daemon, run func(context.Context) error) error {
+	sigCh := make(chan os.Signal, 1)
+	signal.Notify(sigCh, syscall.SIGTERM, syscall.SIGINT)
+	defer signal.Stop(sigCh)
+
+	runCtx, cancelRun := context.WithCancel(ctx)
+	defer cancelRun()
+
+	errCh := make(chan error, 1)
+	go func() { errCh <- run(runCtx) }()
+
+	select {
+	case err := <-errCh:
+		return err
+	case <-sigCh:
+		cancelRun()
+		shutdownErr := daemon.Shutdown(ctx)
+		if shutdownErr != nil {
+			// errCh is buffered, so run's goroutine can still deliver its
+			// result (and exit) once the stragglers return.
+			return shutdownErr
+		}
+		return <-errCh
+	}
+}